@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"context"
+	"time"
+
+	tls "github.com/tjfoc/gmtls"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// ClientOption configures a GRPCClient constructed by NewGRPCClient.
+type ClientOption func(*GRPCClient)
+
+// WithPerRPCCredentials attaches creds to every connection dialed by the
+// resulting GRPCClient as additional, per-call credentials layered on top
+// of the underlying mTLS session -- e.g. the SM2-signed JWTs minted by
+// core/comm/perrpc.SM2TokenSource.
+func WithPerRPCCredentials(creds credentials.PerRPCCredentials) ClientOption {
+	return func(c *GRPCClient) {
+		c.perRPCCreds = creds
+	}
+}
+
+// GRPCClient manages the configuration and creation of gRPC client
+// connections secured with GM-TLS.
+type GRPCClient struct {
+	tlsConfig   *tls.Config
+	tlsOptions  []TLSOption
+	timeout     time.Duration
+	perRPCCreds credentials.PerRPCCredentials
+}
+
+// NewGRPCClient creates a new GRPCClient whose connections are secured
+// with tlsConfig, re-applying tlsOptions on every dial so that CA and
+// certificate updates take effect without reconstructing the client.
+func NewGRPCClient(tlsConfig *tls.Config, tlsOptions []TLSOption, timeout time.Duration, opts ...ClientOption) *GRPCClient {
+	client := &GRPCClient{
+		tlsConfig:  tlsConfig,
+		tlsOptions: tlsOptions,
+		timeout:    timeout,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
+}
+
+// NewConnection dials address, securing the connection with the client's
+// GM-TLS configuration and attaching any configured per-RPC credentials.
+func (c *GRPCClient) NewConnection(address string, dialOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(&DynamicClientCredentials{
+			TLSConfig:  c.tlsConfig,
+			TLSOptions: c.tlsOptions,
+		}),
+	}, dialOpts...)
+	if c.perRPCCreds != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(c.perRPCCreds))
+	}
+
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+		opts = append(opts, grpc.WithBlock())
+	}
+
+	return grpc.DialContext(ctx, address, opts...)
+}
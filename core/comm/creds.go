@@ -9,9 +9,12 @@ package comm
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
+	"sync"
 
 	"github.com/hyperledger/fabric/common/flogging"
+	gmx509 "github.com/tjfoc/gmsm/x509"
 	tls "github.com/tjfoc/gmtls"
 	"github.com/tjfoc/gmtls/gmcredentials"
 	"google.golang.org/grpc/credentials"
@@ -31,11 +34,40 @@ var (
 	alpnProtoStr = []string{"h2"}
 )
 
+// DynamicServerCredentials is implemented by the credentials returned from
+// NewServerTransportCredentials and NewDynamicServerTransportCredentials.
+// Unlike the plain credentials.TransportCredentials interface, it exposes
+// the mutators that let a caller update the in-flight TLS configuration
+// (server certificate, client CAs) without restarting the gRPC server.
+type DynamicServerCredentials interface {
+	credentials.TransportCredentials
+
+	// SetServerCertificate replaces the certificate presented during the
+	// TLS handshake, taking effect on the next ServerHandshake.
+	SetServerCertificate(cert tls.Certificate)
+	// SetClientCAs replaces the pool of CAs used to verify client
+	// certificates.
+	SetClientCAs(cp *gmx509.CertPool)
+	// AppendClientCAs adds one or more PEM-encoded CA certificates to the
+	// pool used to verify client certificates, without discarding CAs
+	// that were already trusted.
+	AppendClientCAs(pemCerts ...[]byte) error
+	// AddALPNProtocol registers proto as an additional application level
+	// protocol offered during the TLS handshake, routing connections
+	// that negotiate it to handler instead of treating them as gRPC
+	// connections.
+	AddALPNProtocol(proto string, handler ProtocolHandler)
+	// SetHandshakeObserver registers observer to be notified of the
+	// outcome of every subsequent ServerHandshake. Passing nil disables
+	// reporting.
+	SetHandshakeObserver(observer HandshakeObserver)
+}
+
 // NewServerTransportCredentials returns a new initialized
 // grpc/credentials.TransportCredentials
 func NewServerTransportCredentials(
 	serverConfig *tls.Config,
-	logger *flogging.FabricLogger) credentials.TransportCredentials {
+	logger *flogging.FabricLogger) DynamicServerCredentials {
 
 	// NOTE: unlike the default grpc/credentials implementation, we do not
 	// clone the tls.Config which allows us to update it dynamically
@@ -48,10 +80,133 @@ func NewServerTransportCredentials(
 		logger:       logger}
 }
 
+// NewDynamicServerTransportCredentials returns a new initialized
+// grpc/credentials.TransportCredentials that, in addition to what the
+// credentials returned by NewServerTransportCredentials do, re-applies
+// tlsOptions to a clone of serverConfig on every ServerHandshake. This
+// allows the root CAs, client CAs and server certificate to be updated
+// while the gRPC server is serving in-flight listeners, mirroring the
+// DynamicClientCredentials pattern used on the client side.
+func NewDynamicServerTransportCredentials(
+	serverConfig *tls.Config,
+	tlsOptions []TLSOption,
+	logger *flogging.FabricLogger) DynamicServerCredentials {
+
+	serverConfig.NextProtos = alpnProtoStr
+	serverConfig.MinVersion = tls.VersionTLS12
+	serverConfig.MaxVersion = tls.VersionTLS12
+	return &serverCreds{
+		serverConfig: serverConfig,
+		tlsOptions:   tlsOptions,
+		logger:       logger}
+}
+
+// ProtocolHandler processes a raw, already TLS-handshaken connection that
+// negotiated an ALPN protocol other than "h2". It is responsible for the
+// lifetime of conn, including closing it once done.
+type ProtocolHandler func(conn net.Conn, negotiatedProtocol string)
+
 // serverCreds is an implementation of grpc/credentials.TransportCredentials.
 type serverCreds struct {
-	serverConfig *tls.Config
-	logger       *flogging.FabricLogger
+	mutex             sync.RWMutex
+	serverConfig      *tls.Config
+	tlsOptions        []TLSOption
+	protocolHandlers  map[string]ProtocolHandler
+	handshakeObserver HandshakeObserver
+	logger            *flogging.FabricLogger
+}
+
+// SetHandshakeObserver registers observer to be notified of the outcome
+// of every subsequent ServerHandshake. Passing nil disables reporting.
+func (sc *serverCreds) SetHandshakeObserver(observer HandshakeObserver) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	sc.handshakeObserver = observer
+}
+
+// latestConfig returns a clone of the base *tls.Config with every
+// registered TLSOption re-applied, so that changes made through the
+// mutators below are picked up by the very next handshake.
+func (sc *serverCreds) latestConfig() *tls.Config {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+	tlsConfigCopy := sc.serverConfig.Clone()
+	for _, tlsOption := range sc.tlsOptions {
+		tlsOption(tlsConfigCopy)
+	}
+	return tlsConfigCopy
+}
+
+// SetServerCertificate replaces the certificate presented during the TLS
+// handshake, taking effect on the next ServerHandshake.
+func (sc *serverCreds) SetServerCertificate(cert tls.Certificate) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	sc.serverConfig.Certificates = []tls.Certificate{cert}
+}
+
+// SetClientCAs replaces the pool of CAs used to verify client certificates.
+func (sc *serverCreds) SetClientCAs(cp *gmx509.CertPool) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	sc.serverConfig.ClientCAs = cp
+}
+
+// AppendClientCAs adds one or more PEM-encoded CA certificates to the pool
+// used to verify client certificates, without discarding CAs that were
+// already trusted.
+func (sc *serverCreds) AppendClientCAs(pemCerts ...[]byte) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	if sc.serverConfig.ClientCAs == nil {
+		sc.serverConfig.ClientCAs = gmx509.NewCertPool()
+	}
+	for _, pemCert := range pemCerts {
+		if !sc.serverConfig.ClientCAs.AppendCertsFromPEM(pemCert) {
+			return errors.New("core/comm: failed to append certificate to client CA pool")
+		}
+	}
+	return nil
+}
+
+// AddALPNProtocol registers proto as an additional application level
+// protocol that this listener will offer during the TLS handshake, and
+// routes connections that negotiate it to handler instead of treating
+// them as gRPC connections. This allows a single GM-TLS listener to
+// multiplex gRPC alongside other protocols, such as gossip or a
+// health/metrics endpoint.
+func (sc *serverCreds) AddALPNProtocol(proto string, handler ProtocolHandler) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	sc.serverConfig.NextProtos = append(sc.serverConfig.NextProtos, proto)
+	if sc.protocolHandlers == nil {
+		sc.protocolHandlers = map[string]ProtocolHandler{}
+	}
+	sc.protocolHandlers[proto] = handler
+}
+
+// TLSInfo wraps gmcredentials.TLSInfo, additionally implementing
+// credentials.ChannelzSecurityInfo so that gRPC's channelz reporting
+// can surface the negotiated cipher suite and peer certificate for
+// GM-TLS connections, which gmcredentials.TLSInfo does not provide on
+// its own.
+type TLSInfo struct {
+	gmcredentials.TLSInfo
+	localCert []byte
+}
+
+// GetSecurityValue implements credentials.ChannelzSecurityInfo.
+func (t TLSInfo) GetSecurityValue() credentials.ChannelzSecurityValue {
+	v := &credentials.TLSChannelzSecurityValue{
+		StandardName: fmt.Sprintf("0x%x", t.State.CipherSuite),
+	}
+	if len(t.State.PeerCertificates) > 0 {
+		v.RemoteCertificate = t.State.PeerCertificates[0].Raw
+	}
+	if len(t.localCert) > 0 {
+		v.LocalCertificate = t.localCert
+	}
+	return v
 }
 
 // ClientHandShake is not implemented for `serverCreds`.
@@ -62,15 +217,62 @@ func (sc *serverCreds) ClientHandshake(context.Context,
 
 // ServerHandshake does the authentication handshake for servers.
 func (sc *serverCreds) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
-	conn := tls.Server(rawConn, sc.serverConfig)
+	remoteAddr := rawConn.RemoteAddr().String()
+	conn := tls.Server(rawConn, sc.latestConfig())
 	if err := conn.Handshake(); err != nil {
 		if sc.logger != nil {
 			sc.logger.With("remote address",
 				conn.RemoteAddr().String()).Errorf("TLS handshake failed with error %s", err)
 		}
+		sc.reportHandshakeFailure(remoteAddr, conn.ConnectionState().ServerName, err)
 		return nil, nil, err
 	}
-	return conn, gmcredentials.TLSInfo{State: conn.ConnectionState()}, nil
+
+	state := conn.ConnectionState()
+	if state.NegotiatedProtocol != "" && state.NegotiatedProtocol != "h2" {
+		sc.mutex.RLock()
+		handler := sc.protocolHandlers[state.NegotiatedProtocol]
+		sc.mutex.RUnlock()
+		if handler == nil {
+			err := fmt.Errorf("core/comm: no handler registered for negotiated protocol %s", state.NegotiatedProtocol)
+			if sc.logger != nil {
+				sc.logger.Errorf("no handler registered for negotiated protocol %s", state.NegotiatedProtocol)
+			}
+			conn.Close()
+			sc.reportHandshakeFailure(remoteAddr, state.ServerName, err)
+			return nil, nil, err
+		}
+		sc.reportHandshakeSuccess(remoteAddr, state)
+		handler(conn, state.NegotiatedProtocol)
+		return nil, nil, credentials.ErrConnDispatched
+	}
+
+	sc.reportHandshakeSuccess(remoteAddr, state)
+	info := TLSInfo{TLSInfo: gmcredentials.TLSInfo{State: state}}
+	sc.mutex.RLock()
+	if len(sc.serverConfig.Certificates) > 0 && len(sc.serverConfig.Certificates[0].Certificate) > 0 {
+		info.localCert = sc.serverConfig.Certificates[0].Certificate[0]
+	}
+	sc.mutex.RUnlock()
+	return conn, info, nil
+}
+
+func (sc *serverCreds) reportHandshakeFailure(remoteAddr, sni string, err error) {
+	sc.mutex.RLock()
+	observer := sc.handshakeObserver
+	sc.mutex.RUnlock()
+	if observer != nil {
+		observer.OnHandshakeFailure(remoteAddr, sni, err)
+	}
+}
+
+func (sc *serverCreds) reportHandshakeSuccess(remoteAddr string, state tls.ConnectionState) {
+	sc.mutex.RLock()
+	observer := sc.handshakeObserver
+	sc.mutex.RUnlock()
+	if observer != nil {
+		observer.OnHandshakeSuccess(remoteAddr, state)
+	}
 }
 
 // Info provides the ProtocolInfo of this TransportCredentials.
@@ -81,10 +283,29 @@ func (sc *serverCreds) Info() credentials.ProtocolInfo {
 	}
 }
 
-// Clone makes a copy of this TransportCredentials.
+// Clone makes a copy of this TransportCredentials, preserving the
+// registered TLSOption chain, ALPN protocol handlers and handshake
+// observer so that dynamic updates, protocol dispatch and metrics
+// reporting all keep applying to the clone as well.
 func (sc *serverCreds) Clone() credentials.TransportCredentials {
-	creds := NewServerTransportCredentials(sc.serverConfig, sc.logger)
-	return creds
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	var protocolHandlers map[string]ProtocolHandler
+	if len(sc.protocolHandlers) > 0 {
+		protocolHandlers = make(map[string]ProtocolHandler, len(sc.protocolHandlers))
+		for proto, handler := range sc.protocolHandlers {
+			protocolHandlers[proto] = handler
+		}
+	}
+
+	return &serverCreds{
+		serverConfig:      sc.serverConfig.Clone(),
+		tlsOptions:        sc.tlsOptions,
+		protocolHandlers:  protocolHandlers,
+		handshakeObserver: sc.handshakeObserver,
+		logger:            sc.logger,
+	}
 }
 
 // OverrideServerName overrides the server name used to verify the hostname
@@ -106,8 +327,38 @@ func (dtc *DynamicClientCredentials) latestConfig() *tls.Config {
 	return tlsConfigCopy
 }
 
+// ClientHandshake performs the TLS handshake directly (rather than
+// delegating to credentials.NewTLS) so that the returned AuthInfo is our
+// own TLSInfo, giving channelz the same cipher suite and peer certificate
+// detail for the client side of a GM-TLS connection as ServerHandshake
+// already provides for the server side.
 func (dtc *DynamicClientCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
-	return credentials.NewTLS(dtc.latestConfig()).ClientHandshake(ctx, authority, rawConn)
+	cfg := dtc.latestConfig()
+	if cfg.ServerName == "" {
+		cfg.ServerName = authority
+	}
+
+	conn := tls.Client(rawConn, cfg)
+	errChannel := make(chan error, 1)
+	go func() {
+		errChannel <- conn.Handshake()
+	}()
+	select {
+	case err := <-errChannel:
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	case <-ctx.Done():
+		conn.Close()
+		return nil, nil, ctx.Err()
+	}
+
+	info := TLSInfo{TLSInfo: gmcredentials.TLSInfo{State: conn.ConnectionState()}}
+	if len(cfg.Certificates) > 0 && len(cfg.Certificates[0].Certificate) > 0 {
+		info.localCert = cfg.Certificates[0].Certificate[0]
+	}
+	return conn, info, nil
 }
 
 func (dtc *DynamicClientCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
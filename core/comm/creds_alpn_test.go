@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"net"
+	"testing"
+
+	tls "github.com/tjfoc/gmtls"
+)
+
+// TestAddALPNProtocolDispatchesToRegisteredHandler verifies that a
+// registered ALPN protocol both advertises itself in NextProtos and ends
+// up in the dispatch table that ServerHandshake consults after a
+// handshake negotiates it.
+func TestAddALPNProtocolDispatchesToRegisteredHandler(t *testing.T) {
+	creds := NewDynamicServerTransportCredentials(&tls.Config{}, nil, nil)
+	sc := creds.(*serverCreds)
+
+	dispatched := make(chan string, 1)
+	creds.AddALPNProtocol("gossip-gm/1", func(conn net.Conn, negotiatedProtocol string) {
+		dispatched <- negotiatedProtocol
+	})
+
+	if !containsProto(sc.serverConfig.NextProtos, "gossip-gm/1") {
+		t.Fatalf("expected gossip-gm/1 to be added to NextProtos, got %v", sc.serverConfig.NextProtos)
+	}
+
+	handler := sc.protocolHandlers["gossip-gm/1"]
+	if handler == nil {
+		t.Fatal("expected a handler to be registered for gossip-gm/1")
+	}
+	handler(nil, "gossip-gm/1")
+
+	select {
+	case proto := <-dispatched:
+		if proto != "gossip-gm/1" {
+			t.Fatalf("unexpected protocol dispatched: %s", proto)
+		}
+	default:
+		t.Fatal("handler was never invoked")
+	}
+}
+
+// TestAddALPNProtocolUnknownProtocolHasNoHandler verifies that the
+// unknown-protocol error path ServerHandshake takes -- looking up a
+// negotiated protocol that was never registered -- finds no handler, which
+// is what drives it to fail the handshake instead of silently dispatching.
+func TestAddALPNProtocolUnknownProtocolHasNoHandler(t *testing.T) {
+	creds := NewDynamicServerTransportCredentials(&tls.Config{}, nil, nil)
+	sc := creds.(*serverCreds)
+
+	creds.AddALPNProtocol("gossip-gm/1", func(net.Conn, string) {})
+
+	if sc.protocolHandlers["http/1.1"] != nil {
+		t.Fatal("expected no handler registered for an unregistered protocol")
+	}
+}
+
+func containsProto(protos []string, proto string) bool {
+	for _, p := range protos {
+		if p == proto {
+			return true
+		}
+	}
+	return false
+}
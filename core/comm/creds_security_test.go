@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"fmt"
+	"testing"
+
+	gmx509 "github.com/tjfoc/gmsm/x509"
+	tls "github.com/tjfoc/gmtls"
+	"github.com/tjfoc/gmtls/gmcredentials"
+	"google.golang.org/grpc/credentials"
+)
+
+// TestTLSInfoGetSecurityValue covers the channelz reporting added for
+// GM-TLS connections: the negotiated cipher suite, the peer's certificate
+// and, when known, the local certificate.
+func TestTLSInfoGetSecurityValue(t *testing.T) {
+	remoteCert := &gmx509.Certificate{Raw: []byte("remote-der-bytes")}
+	localCertDER := []byte("local-der-bytes")
+
+	tests := []struct {
+		name               string
+		info               TLSInfo
+		expectedStandard   string
+		expectedRemoteCert []byte
+		expectedLocalCert  []byte
+	}{
+		{
+			name: "peer certificate and local certificate present",
+			info: TLSInfo{
+				TLSInfo: gmcredentials.TLSInfo{
+					State: tls.ConnectionState{
+						CipherSuite:      0xc02b,
+						PeerCertificates: []*gmx509.Certificate{remoteCert},
+					},
+				},
+				localCert: localCertDER,
+			},
+			expectedStandard:   fmt.Sprintf("0x%x", uint16(0xc02b)),
+			expectedRemoteCert: remoteCert.Raw,
+			expectedLocalCert:  localCertDER,
+		},
+		{
+			name: "no peer certificate and no local certificate",
+			info: TLSInfo{
+				TLSInfo: gmcredentials.TLSInfo{
+					State: tls.ConnectionState{
+						CipherSuite: 0x1301,
+					},
+				},
+			},
+			expectedStandard:   fmt.Sprintf("0x%x", uint16(0x1301)),
+			expectedRemoteCert: nil,
+			expectedLocalCert:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			value := tt.info.GetSecurityValue()
+			tlsValue, ok := value.(*credentials.TLSChannelzSecurityValue)
+			if !ok {
+				t.Fatalf("expected *credentials.TLSChannelzSecurityValue, got %T", value)
+			}
+			if tlsValue.StandardName != tt.expectedStandard {
+				t.Errorf("expected StandardName %q, got %q", tt.expectedStandard, tlsValue.StandardName)
+			}
+			if string(tlsValue.RemoteCertificate) != string(tt.expectedRemoteCert) {
+				t.Errorf("expected RemoteCertificate %q, got %q", tt.expectedRemoteCert, tlsValue.RemoteCertificate)
+			}
+			if string(tlsValue.LocalCertificate) != string(tt.expectedLocalCert) {
+				t.Errorf("expected LocalCertificate %q, got %q", tt.expectedLocalCert, tlsValue.LocalCertificate)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	tls "github.com/tjfoc/gmtls"
+)
+
+var (
+	handshakeFailuresOpts = metrics.CounterOpts{
+		Namespace:    "fabric_comm",
+		Subsystem:    "tls",
+		Name:         "handshake_failures_total",
+		Help:         "The number of server-side TLS handshakes that failed, by reason.",
+		LabelNames:   []string{"reason"},
+		StatsdFormat: "%{#fqname}.%{reason}",
+	}
+	handshakesOpts = metrics.CounterOpts{
+		Namespace:    "fabric_comm",
+		Subsystem:    "tls",
+		Name:         "handshakes_total",
+		Help:         "The number of server-side TLS handshakes that completed successfully, by negotiated cipher and version.",
+		LabelNames:   []string{"cipher", "version"},
+		StatsdFormat: "%{#fqname}.%{cipher}.%{version}",
+	}
+)
+
+// HandshakeObserver is notified of the outcome of every TLS handshake
+// performed by a serverCreds, giving operators a programmatic way to
+// monitor whether SM2/SM3/SM4 negotiation is actually succeeding.
+type HandshakeObserver interface {
+	// OnHandshakeFailure is invoked when ServerHandshake fails, either
+	// during the TLS handshake itself or while dispatching a multiplexed
+	// ALPN connection.
+	OnHandshakeFailure(remoteAddr string, sni string, err error)
+	// OnHandshakeSuccess is invoked once a TLS handshake completes and
+	// the connection state has been obtained.
+	OnHandshakeSuccess(remoteAddr string, state tls.ConnectionState)
+}
+
+// NewMetricsHandshakeObserver returns a HandshakeObserver that reports
+// handshake outcomes as counters through the given metrics.Provider,
+// exposing them as the Prometheus metrics
+// fabric_comm_tls_handshake_failures_total{reason=...} and
+// fabric_comm_tls_handshakes_total{cipher=...,version=...}.
+func NewMetricsHandshakeObserver(provider metrics.Provider) HandshakeObserver {
+	return &metricsHandshakeObserver{
+		failures:   provider.NewCounter(handshakeFailuresOpts),
+		handshakes: provider.NewCounter(handshakesOpts),
+	}
+}
+
+type metricsHandshakeObserver struct {
+	failures   metrics.Counter
+	handshakes metrics.Counter
+}
+
+func (o *metricsHandshakeObserver) OnHandshakeFailure(remoteAddr, sni string, err error) {
+	o.failures.With("reason", classifyHandshakeError(err)).Add(1)
+}
+
+func (o *metricsHandshakeObserver) OnHandshakeSuccess(remoteAddr string, state tls.ConnectionState) {
+	o.handshakes.With(
+		"cipher", fmt.Sprintf("0x%x", state.CipherSuite),
+		"version", fmt.Sprintf("0x%x", state.Version),
+	).Add(1)
+}
+
+// classifyHandshakeError buckets a handshake error returned by tjfoc/gmtls
+// into one of a small set of operator-meaningful reasons.
+func classifyHandshakeError(err error) string {
+	if err == nil {
+		return "unknown"
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "unknown certificate authority") || strings.Contains(msg, "unknown authority"):
+		return "unknown-ca"
+	case strings.Contains(msg, "expired"):
+		return "cert-expired"
+	case strings.Contains(msg, "no cipher suite") || strings.Contains(msg, "cipher suite"):
+		return "no-cipher-overlap"
+	case strings.Contains(msg, "protocol version"):
+		return "protocol-version"
+	case strings.Contains(msg, "connection reset") || strings.Contains(msg, "eof") || strings.Contains(msg, "broken pipe"):
+		return "client-abort"
+	default:
+		return "unknown"
+	}
+}
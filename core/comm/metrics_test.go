@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	tls "github.com/tjfoc/gmtls"
+)
+
+func TestClassifyHandshakeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"nil error", nil, "unknown"},
+		{"unknown CA", errors.New("x509: certificate signed by unknown authority"), "unknown-ca"},
+		{"unknown certificate authority", errors.New("gmtls: failed to verify certificate: unknown certificate authority"), "unknown-ca"},
+		{"expired certificate", errors.New("x509: certificate has expired or is not yet valid"), "cert-expired"},
+		{"no cipher overlap", errors.New("gmtls: no cipher suite supported by both client and server"), "no-cipher-overlap"},
+		{"protocol version mismatch", errors.New("gmtls: client offered only unsupported protocol version"), "protocol-version"},
+		{"client abort via reset", errors.New("read tcp 127.0.0.1:1234: connection reset by peer"), "client-abort"},
+		{"client abort via eof", errors.New("unexpected EOF"), "client-abort"},
+		{"unrecognized error", errors.New("something else entirely"), "unknown"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyHandshakeError(tt.err); got != tt.expected {
+				t.Errorf("classifyHandshakeError(%v) = %q, want %q", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+type fakeCounter struct {
+	labelValues []string
+	adds        []float64
+}
+
+func (c *fakeCounter) With(labelValues ...string) metrics.Counter {
+	c.labelValues = labelValues
+	return c
+}
+
+func (c *fakeCounter) Add(delta float64) {
+	c.adds = append(c.adds, delta)
+}
+
+type fakeProvider struct {
+	counters map[string]*fakeCounter
+}
+
+func newFakeProvider() *fakeProvider {
+	return &fakeProvider{counters: map[string]*fakeCounter{}}
+}
+
+func (p *fakeProvider) NewCounter(opts metrics.CounterOpts) metrics.Counter {
+	c := &fakeCounter{}
+	p.counters[opts.Name] = c
+	return c
+}
+
+func (p *fakeProvider) NewGauge(opts metrics.GaugeOpts) metrics.Gauge {
+	panic("not implemented for this test")
+}
+
+func (p *fakeProvider) NewHistogram(opts metrics.HistogramOpts) metrics.Histogram {
+	panic("not implemented for this test")
+}
+
+func TestMetricsHandshakeObserverReportsFailuresAndSuccesses(t *testing.T) {
+	provider := newFakeProvider()
+	observer := NewMetricsHandshakeObserver(provider)
+
+	observer.OnHandshakeFailure("127.0.0.1:1234", "peer.example.com", errors.New("x509: certificate has expired or is not yet valid"))
+
+	failures := provider.counters[handshakeFailuresOpts.Name]
+	if failures == nil {
+		t.Fatal("expected a counter to have been created for handshake failures")
+	}
+	if len(failures.labelValues) != 2 || failures.labelValues[0] != "reason" || failures.labelValues[1] != "cert-expired" {
+		t.Fatalf("expected reason=cert-expired label, got %v", failures.labelValues)
+	}
+	if len(failures.adds) != 1 || failures.adds[0] != 1 {
+		t.Fatalf("expected a single increment, got %v", failures.adds)
+	}
+
+	observer.OnHandshakeSuccess("127.0.0.1:1234", tls.ConnectionState{CipherSuite: 0xc02b, Version: tls.VersionTLS12})
+
+	handshakes := provider.counters[handshakesOpts.Name]
+	if handshakes == nil {
+		t.Fatal("expected a counter to have been created for successful handshakes")
+	}
+	if len(handshakes.labelValues) != 4 ||
+		handshakes.labelValues[0] != "cipher" || handshakes.labelValues[1] != "0xc02b" ||
+		handshakes.labelValues[2] != "version" || handshakes.labelValues[3] != "0x303" {
+		t.Fatalf("unexpected labels for successful handshake counter: %v", handshakes.labelValues)
+	}
+	if len(handshakes.adds) != 1 || handshakes.adds[0] != 1 {
+		t.Fatalf("expected a single increment, got %v", handshakes.adds)
+	}
+}